@@ -1,236 +1,102 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/emersion/go-imap"
-	"github.com/emersion/go-imap/client"
 	"github.com/mbndr/figlet4go"
-	"github.com/schollz/progressbar/v3"
-)
 
-// Progress counters
-var (
-	checkedCount uint64
-	liveCount    uint64
-	totalCount   uint64
-	bar          *progressbar.ProgressBar
-	messageMutex sync.Mutex
+	"imap-checker/pkg/checker"
+	"imap-checker/pkg/creds"
+	"imap-checker/pkg/daemon"
+	"imap-checker/pkg/discover"
+	"imap-checker/pkg/mstore"
+	"imap-checker/pkg/report"
+	"imap-checker/pkg/resolve"
+	"imap-checker/pkg/rules"
 )
 
-// ImapServers holds the mapping of email domains to their IMAP servers
-var ImapServers map[string]string
-
-type Credential struct {
-	Email    string
-	Password string
-}
-
-func readCredentials(filePath string) ([]Credential, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	var credentials []Credential
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
+// discoverCacheTTL controls how long a discovered domain's server config is
+// trusted before discover.Domain re-probes it.
+const discoverCacheTTL = 7 * 24 * time.Hour
 
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
+func processAccount(cred creds.Credential, insecureSkipVerify, saveAttachments bool, dir resolve.Directory, cache *discover.Cache, ruleSet []rules.Rule, progress *report.Progress, hits report.OutputSink, extracts *report.TextSink, wg *sync.WaitGroup) {
+	live := false
+	status := report.AccountResult{Email: cred.Email, Status: "dead"}
+	defer func() {
+		status.CheckedAt = time.Now()
+		if err := hits.WriteAccount(status); err != nil {
+			fmt.Printf("main: %s: write account: %v\n", cred.Email, err)
 		}
+		progress.MarkChecked(live)
+		wg.Done()
+	}()
 
-		credentials = append(credentials, Credential{
-			Email:    strings.TrimSpace(parts[0]),
-			Password: strings.TrimSpace(parts[1]),
-		})
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %v", err)
-	}
-
-	return credentials, nil
-}
-
-func loadImapServers() error {
-	file, err := os.ReadFile("imap_servers.json")
-	if err != nil {
-		return fmt.Errorf("failed to read IMAP servers file: %v", err)
-	}
-
-	if err := json.Unmarshal(file, &ImapServers); err != nil {
-		return fmt.Errorf("failed to parse IMAP servers file: %v", err)
-	}
-
-	return nil
-}
-
-func login(cred Credential) (*client.Client, error) {
-	parts := strings.Split(cred.Email, "@")
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid email format")
-	}
-	domain := parts[1]
-
-	imapServer, ok := ImapServers[domain]
-	if !ok {
-		// Fallback to generic format if domain not found in config
-		imapServer = fmt.Sprintf("imap.%s", domain)
-	}
-
-	c, err := client.DialTLS(imapServer+":993", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %v", err)
-	}
-
-	if err := c.Login(cred.Email, cred.Password); err != nil {
-		c.Logout()
-		return nil, fmt.Errorf("login failed: %v", err)
-	}
-
-	return c, nil
-}
-
-func appendToMessages(content string) {
-	messageMutex.Lock()
-	defer messageMutex.Unlock()
-
-	file, err := os.OpenFile("messages.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	config, err := dir.Config(cred.Email, cache)
 	if err != nil {
-		fmt.Printf("Error opening messages file: %v\n", err)
+		status.Error = err.Error()
 		return
 	}
-	defer file.Close()
-
-	if _, err := file.WriteString(content); err != nil {
-		fmt.Printf("Error writing messages: %v\n", err)
+	// A credential line that supplies an OAuth2 token always wins over
+	// whatever auth mode the server config assumes.
+	if cred.Auth == creds.AuthXOAuth2 {
+		config.Auth = mstore.AuthXOAuth2
 	}
-}
 
-func processAccount(cred Credential, wg *sync.WaitGroup, liveChan chan<- string) {
-	defer func() {
-		atomic.AddUint64(&checkedCount, 1)
-		desc := fmt.Sprintf("[cyan]Checked:[reset] %d [green]Live:[reset] %d [yellow]Total:[reset] %d",
-			atomic.LoadUint64(&checkedCount),
-			atomic.LoadUint64(&liveCount),
-			atomic.LoadUint64(&totalCount))
-		bar.Describe(desc)
-		bar.Add(1)
-	}()
-	defer wg.Done()
-
-	c, err := login(cred)
-	if err != nil {
+	store := mstore.NewIMAPStore(config, cred.Email, cred.Secret)
+	store.InsecureSkipVerify = insecureSkipVerify
+	if err := store.Connect(); err != nil {
+		status.Error = err.Error()
 		return
 	}
-	defer c.Logout()
-
-	atomic.AddUint64(&liveCount, 1)
-
-	totalMessages := make(map[string]int)
-	senders := []string{"booking.com", "netflix.com"}
-
-	c.Select("INBOX", false)
-
-	for _, sender := range senders {
-		criteria := imap.NewSearchCriteria()
-		criteria.Header.Add("From", sender)
-
-		// Add date criteria for 2025
-		since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
-		before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
-		criteria.Since = since
-		criteria.Before = before
-
-		if uids, err := c.Search(criteria); err == nil {
-			totalMessages[sender] = len(uids)
-
-			if len(uids) > 0 {
-				seqSet := new(imap.SeqSet)
-				seqSet.AddNum(uids...)
+	defer store.Logout()
 
-				messages := make(chan *imap.Message, 10)
-				done := make(chan error, 1)
+	live = true
+	status.Status = "live"
 
-				go func() {
-					done <- c.Fetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, imap.FetchBody, imap.FetchBodyStructure}, messages)
-				}()
+	hitCounts := checker.RunRules(store, cred.Email, ruleSet, hits, extracts, saveAttachments, nil)
 
-				var messageDetails []string
-				for msg := range messages {
-					if msg.Envelope != nil {
-						fromName := "Unknown"
-						fromEmail := "Unknown"
-						if len(msg.Envelope.From) > 0 {
-							if msg.Envelope.From[0].PersonalName != "" {
-								fromName = msg.Envelope.From[0].PersonalName
-							}
-							if addr := msg.Envelope.From[0].MailboxName + "@" + msg.Envelope.From[0].HostName; addr != "@" {
-								fromEmail = addr
-							}
-						}
-
-						// Ensure the message is from 2025
-						if msg.Envelope.Date.Year() == 2025 {
-							detail := fmt.Sprintf("\nFrom Name: %s\nFrom Email: %s\nTo: %s\nSubject: %s\nDate: %s\nAccount: %s\n---",
-								fromName,
-								fromEmail,
-								cred.Email,
-								msg.Envelope.Subject,
-								msg.Envelope.Date.Format("2006-01-02 15:04:05"),
-								cred.Email)
-							messageDetails = append(messageDetails, detail)
-						}
-					}
-				}
-				<-done
-
-				if len(messageDetails) > 0 {
-					content := fmt.Sprintf("\n\n=== Messages from %s ===\n%s\n",
-						sender, strings.Join(messageDetails, "\n"))
-					// Only append messages from the specified senders and year
-					appendToMessages(content)
-				}
-			}
-		}
-	}
-
-	var messageSummary []string
-	for sender, count := range totalMessages {
-		messageSummary = append(messageSummary, fmt.Sprintf("%s: %d", sender, count))
+	var ruleSummary []string
+	for _, rule := range ruleSet {
+		ruleSummary = append(ruleSummary, fmt.Sprintf("%s: %d", rule.Name, hitCounts[rule.Name]))
 	}
 
-	result := fmt.Sprintf("%s:%s | %s", cred.Email, cred.Password, strings.Join(messageSummary, ", "))
-	liveChan <- result
+	result := fmt.Sprintf("%s:%s | %s", cred.Email, cred.Secret, strings.Join(ruleSummary, ", "))
+	extracts.WriteLive(result)
 }
 
 func main() {
-	if err := loadImapServers(); err != nil {
+	insecureSkipVerify := flag.Bool("insecure-skip-verify", false, "skip TLS certificate verification (self-hosted servers with untrusted certs)")
+	daemonMode := flag.Bool("daemon", false, "after the initial pass, stay connected and re-scan accounts as new mail arrives via IMAP IDLE")
+	pollInterval := flag.Duration("poll-interval", 5*time.Minute, "fallback poll period in daemon mode for servers without IDLE support")
+	saveAttachments := flag.Bool("save-attachments", false, "save message attachments to attachments/<account>/<uid>_<filename>")
+	output := flag.String("output", "text", "comma-separated output sinks for rule hits and account status: text, jsonl, sqlite")
+	flag.Parse()
+
+	dir, err := resolve.LoadDirectory("imap_servers.json")
+	if err != nil {
 		fmt.Printf("Error: Failed to load IMAP servers: %v\n", err)
 		return
 	}
 
-	credentials, err := readCredentials("data.txt")
+	credentials, err := creds.LoadFile("data.txt")
 	if err != nil {
 		fmt.Printf("Error: Failed to read credentials: %v\n", err)
 		return
 	}
 
-	atomic.StoreUint64(&totalCount, uint64(len(credentials)))
+	ruleSet, err := rules.LoadFile("rules.yaml")
+	if err != nil {
+		fmt.Printf("Error: Failed to load rules: %v\n", err)
+		return
+	}
 
 	// Initialize screen and show header
 	fmt.Printf("\033[2J") // Clear screen
@@ -248,55 +114,55 @@ func main() {
 	// Show author and time
 	loc, _ := time.LoadLocation("Asia/Jakarta")
 	fmt.Printf("\033[36m Made by @agp0x\033[0m | \033[33m%s WIB\033[0m\n\n", time.Now().In(loc).Format("15:04:05"))
-	bar = progressbar.NewOptions(len(credentials),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionSetDescription("[cyan]Starting...[reset]"),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]█[reset]",
-			SaucerHead:    "[green]█[reset]",
-			SaucerPadding: "░",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Println() // Add newline after completion
-		}))
 
-	var wg sync.WaitGroup
-	liveChan := make(chan string, len(credentials))
-	semaphore := make(chan struct{}, 300)
+	progress := report.NewProgress(len(credentials))
 
-	// Open file with synchronous writing for real-time updates
-	liveFile, err := os.OpenFile("live.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY|os.O_SYNC, 0644)
+	extracts, err := report.NewTextSink("live.txt")
 	if err != nil {
-		fmt.Printf("Error: Failed to open live.txt: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		return
 	}
-	defer liveFile.Close()
+	defer extracts.Close()
 
-	// Start a dedicated goroutine for real-time file writing
-	go func() {
-		for result := range liveChan {
-			// Write directly to file for immediate saving
-			if _, err := fmt.Fprintln(liveFile, result); err != nil {
-				fmt.Printf("Error: Failed to write to live.txt: %v\n", err)
-			}
-		}
-	}()
+	hits, err := report.NewOutputSinks(*output, extracts)
+	if err != nil {
+		fmt.Printf("Error: Failed to initialize output sinks: %v\n", err)
+		return
+	}
+	defer hits.Close()
+
+	cache := discover.NewCache("discover_cache.json", discoverCacheTTL)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 300)
 
-	// Start worker goroutines
 	for _, cred := range credentials {
 		wg.Add(1)
 		semaphore <- struct{}{}
-		go func(cred Credential) {
-			processAccount(cred, &wg, liveChan)
+		go func(cred creds.Credential) {
+			processAccount(cred, *insecureSkipVerify, *saveAttachments, dir, cache, ruleSet, progress, hits, extracts, &wg)
 			<-semaphore
 		}(cred)
 	}
 
-	// Wait for all workers to complete
 	wg.Wait()
-	close(liveChan)
+
+	if !*daemonMode {
+		return
+	}
+
+	fmt.Println("\nInitial pass complete, entering daemon mode (Ctrl-C to stop)...")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	daemon.Run(ctx, credentials, ruleSet, hits, extracts, daemon.Config{
+		Directory:          dir,
+		Cache:              cache,
+		InsecureSkipVerify: *insecureSkipVerify,
+		PollInterval:       *pollInterval,
+		SaveAttachments:    *saveAttachments,
+	})
+
+	fmt.Println("Shutting down, logged out all sessions.")
 }
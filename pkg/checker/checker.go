@@ -0,0 +1,269 @@
+// Package checker runs a rule set against a connected mailbox store and
+// reports the hits. It's shared by the one-shot batch pass in main and the
+// daemon's re-scan-on-new-mail loop.
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emersion/go-imap"
+
+	"imap-checker/pkg/mailbody"
+	"imap-checker/pkg/mstore"
+	"imap-checker/pkg/report"
+	"imap-checker/pkg/rules"
+)
+
+// attachmentsDir is where saved attachments are written, under a
+// per-account subdirectory.
+const attachmentsDir = "attachments"
+
+// snippetLen caps how much of a decoded body is carried into a report.Hit.
+const snippetLen = 200
+
+// ExtractRecord is one regex extractor match, written as a line of a rule's
+// extracts_<rule>.jsonl file.
+type ExtractRecord struct {
+	Account   string   `json:"account"`
+	Rule      string   `json:"rule"`
+	UID       uint32   `json:"uid"`
+	From      string   `json:"from"`
+	Subject   string   `json:"subject"`
+	Date      string   `json:"date"`
+	Extractor string   `json:"extractor"`
+	Match     string   `json:"match"`
+	Groups    []string `json:"groups"`
+}
+
+// UIDWatermarks tracks, per mailbox, the UID a rule search should resume
+// from. RunRules uses it to only match newly arrived mail on repeat calls
+// against the same store instead of re-matching the whole mailbox: a nil
+// map disables the behavior and always searches the full mailbox.
+type UIDWatermarks map[string]uint32
+
+// RunRules executes each rule in ruleSet against store, writing a hit to
+// hits for every matching message, extractor matches to extracts' per-rule
+// JSONL files, and optionally saving attachments to disk. It returns the
+// number of hits found per rule name.
+//
+// When watermarks is non-nil, every search of a mailbox in this call is
+// restricted to UIDs at or after the watermark the mailbox had when the call
+// began (if one is set yet), and the watermark is advanced to the mailbox's
+// current UIDNEXT when it is first selected, so the next call only sees mail
+// that arrived in between.
+func RunRules(store mstore.Store, email string, ruleSet []rules.Rule, hits report.OutputSink, extracts *report.TextSink, saveAttachments bool, watermarks UIDWatermarks) map[string]int {
+	hitCounts := make(map[string]int)
+	selectedMailbox := ""
+	bodySection := &imap.BodySectionName{}
+
+	// Snapshot each mailbox's resume point once, before any rule in this
+	// call advances it. Without this, a rule set that revisits a mailbox
+	// (e.g. [INBOX, Archive, INBOX]) would have its second INBOX rule
+	// re-read the watermark the first INBOX rule just advanced, searching
+	// from the wrong UID instead of where this call started.
+	resumeFrom := make(map[string]uint32, len(watermarks))
+	for mailbox, since := range watermarks {
+		resumeFrom[mailbox] = since
+	}
+	advanced := make(map[string]bool)
+
+	for _, rule := range ruleSet {
+		mailbox := rule.MailboxOrDefault()
+		if mailbox != selectedMailbox {
+			status, err := store.Select(mailbox, false)
+			if err != nil {
+				continue
+			}
+			selectedMailbox = mailbox
+
+			if watermarks != nil && !advanced[mailbox] {
+				watermarks[mailbox] = status.UidNext
+				advanced[mailbox] = true
+			}
+		}
+
+		criteria, err := rule.SearchCriteria()
+		if err != nil {
+			continue
+		}
+		if since, ok := resumeFrom[mailbox]; ok {
+			criteria.Uid = uidsSince(since)
+		}
+
+		uids, err := store.Search(criteria)
+		if err != nil {
+			continue
+		}
+		hitCounts[rule.Name] = len(uids)
+		if len(uids) == 0 {
+			continue
+		}
+
+		extractors, err := rule.CompiledExtractors()
+		if err != nil {
+			continue
+		}
+
+		items := []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, imap.FetchBodyStructure, bodySection.FetchItem()}
+		messages, err := store.Fetch(uids, items)
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			var decoded mailbody.Decoded
+			if body := msg.GetBody(bodySection); body != nil {
+				decoded, _ = mailbody.Decode(body, saveAttachments)
+			}
+
+			if msg.Envelope != nil {
+				if err := hits.WriteHit(hitFromMessage(rule, email, msg, decoded.Text)); err != nil {
+					fmt.Printf("checker: %s: write hit for rule %q: %v\n", email, rule.Name, err)
+				}
+			}
+
+			processBody(msg, rule, email, decoded, extractors, saveAttachments, extracts)
+		}
+	}
+
+	return hitCounts
+}
+
+// hitFromMessage builds the report.Hit for one matched message, including
+// only the envelope/body parts rule.Extract names (or all of them, if the
+// rule doesn't set Extract).
+func hitFromMessage(rule rules.Rule, email string, msg *imap.Message, bodyText string) report.Hit {
+	h := report.Hit{
+		Account: email,
+		Rule:    rule.Name,
+		UID:     msg.Uid,
+		Headers: map[string]string{},
+	}
+
+	if rule.Extracts("body") {
+		h.Snippet = snippet(bodyText)
+	}
+	if rule.Extracts("from") {
+		h.From = addressString(msg.Envelope.From)
+		h.Headers["From"] = h.From
+	}
+	if rule.Extracts("to") {
+		h.To = addressString(msg.Envelope.To)
+		h.Headers["To"] = h.To
+	}
+	if rule.Extracts("subject") {
+		h.Subject = msg.Envelope.Subject
+		h.Headers["Subject"] = h.Subject
+	}
+	if rule.Extracts("date") {
+		h.Date = msg.Envelope.Date.Format("2006-01-02 15:04:05")
+		h.Headers["Date"] = h.Date
+	}
+
+	return h
+}
+
+// PrimeWatermarks selects every mailbox referenced by ruleSet and records
+// its current UIDNEXT into watermarks, without running any rule or
+// emitting hits. Daemon mode calls this right after connecting so its
+// first RunRules call only reports mail that arrives after that point,
+// instead of re-emitting everything the initial batch pass already
+// reported for the same account.
+func PrimeWatermarks(store mstore.Store, ruleSet []rules.Rule, watermarks UIDWatermarks) error {
+	seen := make(map[string]bool, len(ruleSet))
+	for _, rule := range ruleSet {
+		mailbox := rule.MailboxOrDefault()
+		if seen[mailbox] {
+			continue
+		}
+		seen[mailbox] = true
+
+		status, err := store.Select(mailbox, false)
+		if err != nil {
+			return err
+		}
+		watermarks[mailbox] = status.UidNext
+	}
+	return nil
+}
+
+// uidsSince builds the IMAP "since:*" UID range used to restrict a search to
+// messages at or after since.
+func uidsSince(since uint32) *imap.SeqSet {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddRange(since, 0)
+	return seqSet
+}
+
+// addressString formats the first entry of addrs as "Name <email>", or just
+// "email" when there's no display name.
+func addressString(addrs []*imap.Address) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+
+	addr := addrs[0]
+	email := addr.MailboxName + "@" + addr.HostName
+	if addr.PersonalName != "" {
+		return fmt.Sprintf("%s <%s>", addr.PersonalName, email)
+	}
+	return email
+}
+
+// snippet trims text and caps it to snippetLen bytes for storage in a Hit.
+func snippet(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > snippetLen {
+		return text[:snippetLen]
+	}
+	return text
+}
+
+func processBody(msg *imap.Message, rule rules.Rule, email string, decoded mailbody.Decoded, extractors []rules.CompiledExtractor, saveAttachments bool, extracts *report.TextSink) {
+	for _, extractor := range extractors {
+		for _, match := range extractor.Regexp.FindAllStringSubmatch(decoded.Text, -1) {
+			record := ExtractRecord{
+				Account:   email,
+				Rule:      rule.Name,
+				UID:       msg.Uid,
+				Extractor: extractor.Name,
+				Match:     match[0],
+				Groups:    match[1:],
+			}
+			if msg.Envelope != nil {
+				if len(msg.Envelope.From) > 0 {
+					record.From = msg.Envelope.From[0].MailboxName + "@" + msg.Envelope.From[0].HostName
+				}
+				record.Subject = msg.Envelope.Subject
+				record.Date = msg.Envelope.Date.Format("2006-01-02 15:04:05")
+			}
+			extracts.WriteExtract(rule.Name, record)
+		}
+	}
+
+	if saveAttachments {
+		saveMessageAttachments(email, msg.Uid, decoded.Attachments)
+	}
+}
+
+func saveMessageAttachments(email string, uid uint32, attachments []mailbody.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+
+	dir := filepath.Join(attachmentsDir, email)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	for _, att := range attachments {
+		if att.Filename == "" {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%d_%s", uid, filepath.Base(att.Filename)))
+		os.WriteFile(path, att.Data, 0644)
+	}
+}
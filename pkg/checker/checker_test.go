@@ -0,0 +1,163 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/emersion/go-imap"
+
+	"imap-checker/pkg/report"
+	"imap-checker/pkg/rules"
+)
+
+// mailbox is one fakeStore mailbox: its current UIDNEXT and the UIDs it
+// holds.
+type mailbox struct {
+	uidNext uint32
+	uids    []uint32
+}
+
+// fakeStore is a minimal mstore.Store backed by in-memory mailboxes, so
+// RunRules' mailbox selection and UID-watermark logic can be tested without
+// a real IMAP server.
+type fakeStore struct {
+	mailboxes map[string]*mailbox
+	selected  string
+}
+
+func (f *fakeStore) Connect() error { return nil }
+
+func (f *fakeStore) Select(name string, readOnly bool) (*imap.MailboxStatus, error) {
+	mb, ok := f.mailboxes[name]
+	if !ok {
+		return nil, fmt.Errorf("no such mailbox %q", name)
+	}
+	f.selected = name
+	return &imap.MailboxStatus{UidNext: mb.uidNext}, nil
+}
+
+func (f *fakeStore) Search(criteria *imap.SearchCriteria) ([]uint32, error) {
+	var out []uint32
+	for _, uid := range f.mailboxes[f.selected].uids {
+		if criteria.Uid != nil && !criteria.Uid.Contains(uid) {
+			continue
+		}
+		out = append(out, uid)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) Fetch(uids []uint32, items []imap.FetchItem) ([]*imap.Message, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) Logout() error { return nil }
+
+type nopSink struct{}
+
+func (nopSink) WriteHit(report.Hit) error               { return nil }
+func (nopSink) WriteAccount(report.AccountResult) error { return nil }
+func (nopSink) Close() error                            { return nil }
+
+func TestRunRulesWatermarking(t *testing.T) {
+	tests := []struct {
+		name       string
+		mailboxes  map[string]*mailbox
+		ruleSet    []rules.Rule
+		watermarks UIDWatermarks // nil disables the feature entirely
+		wantCounts map[string]int
+		wantMarks  UIDWatermarks
+	}{
+		{
+			name: "nil watermarks always does a full scan",
+			mailboxes: map[string]*mailbox{
+				"INBOX": {uidNext: 11, uids: []uint32{5, 6, 7}},
+			},
+			ruleSet:    []rules.Rule{{Name: "r1"}},
+			watermarks: nil,
+			wantCounts: map[string]int{"r1": 3},
+		},
+		{
+			name: "first call with empty watermarks scans everything and primes them",
+			mailboxes: map[string]*mailbox{
+				"INBOX": {uidNext: 11, uids: []uint32{5, 6, 7}},
+			},
+			ruleSet:    []rules.Rule{{Name: "r1"}},
+			watermarks: UIDWatermarks{},
+			wantCounts: map[string]int{"r1": 3},
+			wantMarks:  UIDWatermarks{"INBOX": 11},
+		},
+		{
+			name: "later call only sees UIDs at or after the watermark",
+			mailboxes: map[string]*mailbox{
+				"INBOX": {uidNext: 13, uids: []uint32{5, 6, 7, 11, 12}},
+			},
+			ruleSet:    []rules.Rule{{Name: "r1"}},
+			watermarks: UIDWatermarks{"INBOX": 11},
+			wantCounts: map[string]int{"r1": 2},
+			wantMarks:  UIDWatermarks{"INBOX": 13},
+		},
+		{
+			name: "a mailbox revisited by a later rule keeps the call's starting watermark",
+			mailboxes: map[string]*mailbox{
+				"INBOX":   {uidNext: 13, uids: []uint32{5, 6, 7, 11, 12}},
+				"Archive": {uidNext: 21, uids: []uint32{15, 16}},
+			},
+			ruleSet: []rules.Rule{
+				{Name: "r1", Mailbox: "INBOX"},
+				{Name: "r2", Mailbox: "Archive"},
+				{Name: "r3", Mailbox: "INBOX"},
+			},
+			watermarks: UIDWatermarks{"INBOX": 11},
+			wantCounts: map[string]int{"r1": 2, "r2": 2, "r3": 2},
+			wantMarks:  UIDWatermarks{"INBOX": 13, "Archive": 21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{mailboxes: tt.mailboxes}
+
+			gotCounts := RunRules(store, "a@b.com", tt.ruleSet, nopSink{}, nil, false, tt.watermarks)
+
+			for rule, want := range tt.wantCounts {
+				if got := gotCounts[rule]; got != want {
+					t.Errorf("counts[%q] = %d, want %d", rule, got, want)
+				}
+			}
+
+			for mailbox, want := range tt.wantMarks {
+				if got := tt.watermarks[mailbox]; got != want {
+					t.Errorf("watermarks[%q] = %d, want %d", mailbox, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrimeWatermarks(t *testing.T) {
+	store := &fakeStore{mailboxes: map[string]*mailbox{
+		"INBOX":   {uidNext: 42, uids: []uint32{1, 2, 3}},
+		"Archive": {uidNext: 7, uids: nil},
+	}}
+	ruleSet := []rules.Rule{
+		{Name: "r1", Mailbox: "INBOX"},
+		{Name: "r2", Mailbox: "Archive"},
+		{Name: "r3", Mailbox: "INBOX"},
+	}
+
+	watermarks := make(UIDWatermarks)
+	if err := PrimeWatermarks(store, ruleSet, watermarks); err != nil {
+		t.Fatalf("PrimeWatermarks: %v", err)
+	}
+
+	want := UIDWatermarks{"INBOX": 42, "Archive": 7}
+	if len(watermarks) != len(want) {
+		t.Fatalf("watermarks = %v, want %v", watermarks, want)
+	}
+	for mailbox, uid := range want {
+		if watermarks[mailbox] != uid {
+			t.Errorf("watermarks[%q] = %d, want %d", mailbox, watermarks[mailbox], uid)
+		}
+	}
+}
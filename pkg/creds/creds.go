@@ -0,0 +1,122 @@
+// Package creds loads account credentials from the various file formats the
+// checker accepts: plain "email:password" text, JSON, and CSV.
+package creds
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AuthXOAuth2 marks a Credential whose Secret is an OAuth2 bearer token
+// rather than a plain password.
+const AuthXOAuth2 = "xoauth2"
+
+// Credential is a single account to check. Secret holds the password, or an
+// OAuth2 bearer token when Auth is AuthXOAuth2.
+type Credential struct {
+	Email  string `json:"email"`
+	Secret string `json:"secret"`
+	Auth   string `json:"auth"`
+}
+
+// LoadFile reads credentials from filePath, picking a parser based on the
+// file extension (.json, .csv, anything else is treated as "email:password"
+// text, one per line).
+func LoadFile(filePath string) ([]Credential, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return loadJSON(file)
+	case ".csv":
+		return loadCSV(file)
+	default:
+		return loadText(file)
+	}
+}
+
+// loadText parses "email:password" lines, plus an "email:xoauth2:token"
+// variant for accounts authenticating with an OAuth2 bearer token.
+func loadText(r io.Reader) ([]Credential, error) {
+	var credentials []Credential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+
+		cred := Credential{Email: strings.TrimSpace(parts[0])}
+		if len(parts) == 3 && strings.EqualFold(strings.TrimSpace(parts[1]), AuthXOAuth2) {
+			cred.Auth = AuthXOAuth2
+			cred.Secret = strings.TrimSpace(parts[2])
+		} else {
+			cred.Secret = strings.TrimSpace(strings.Join(parts[1:], ":"))
+		}
+
+		credentials = append(credentials, cred)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	return credentials, nil
+}
+
+func loadJSON(r io.Reader) ([]Credential, error) {
+	var credentials []Credential
+	if err := json.NewDecoder(r).Decode(&credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON credentials: %v", err)
+	}
+	return credentials, nil
+}
+
+// loadCSV expects an "email,secret[,auth]" header followed by one row per
+// account; the header is optional and skipped if present. auth defaults to
+// plain-password login when omitted.
+func loadCSV(r io.Reader) ([]Credential, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV credentials: %v", err)
+	}
+
+	var credentials []Credential
+	for i, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		email := strings.TrimSpace(record[0])
+		secret := strings.TrimSpace(record[1])
+
+		if i == 0 && strings.EqualFold(email, "email") {
+			continue
+		}
+
+		cred := Credential{Email: email, Secret: secret}
+		if len(record) >= 3 {
+			cred.Auth = strings.ToLower(strings.TrimSpace(record[2]))
+		}
+		credentials = append(credentials, cred)
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,166 @@
+// Package daemon keeps live accounts connected after the initial pass and
+// re-runs the configured rules whenever new mail arrives, using IMAP IDLE
+// (or a polling fallback) instead of re-scanning mailboxes from scratch.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"imap-checker/pkg/checker"
+	"imap-checker/pkg/creds"
+	"imap-checker/pkg/discover"
+	"imap-checker/pkg/mstore"
+	"imap-checker/pkg/report"
+	"imap-checker/pkg/resolve"
+	"imap-checker/pkg/rules"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Config controls daemon mode.
+type Config struct {
+	Directory          resolve.Directory
+	Cache              *discover.Cache
+	InsecureSkipVerify bool
+	// PollInterval is the fallback NOOP poll period for servers that don't
+	// advertise IDLE. Zero uses go-imap-idle's default.
+	PollInterval time.Duration
+	// SaveAttachments mirrors the --save-attachments flag.
+	SaveAttachments bool
+}
+
+// Run watches every credential's account, re-running ruleSet against new
+// mail as it arrives, until ctx is cancelled. It blocks until every
+// account's watchAccount goroutine has returned (and so logged out),
+// not just until ctx is done, so callers can rely on Run's return to mean
+// every session is closed.
+func Run(ctx context.Context, credentials []creds.Credential, ruleSet []rules.Rule, hits report.OutputSink, extracts *report.TextSink, cfg Config) {
+	done := make(chan struct{})
+	remaining := len(credentials)
+	if remaining == 0 {
+		return
+	}
+
+	finished := make(chan struct{}, remaining)
+	for _, cred := range credentials {
+		go func(cred creds.Credential) {
+			watchAccount(ctx, cred, ruleSet, hits, extracts, cfg)
+			finished <- struct{}{}
+		}(cred)
+	}
+
+	go func() {
+		for i := 0; i < remaining; i++ {
+			<-finished
+		}
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		<-done
+	case <-done:
+	}
+}
+
+// watchAccount connects cred, primes its UID watermarks (the caller's
+// initial batch pass has already reported whatever mail currently exists,
+// so this just records where to resume from), then idles for new mail and
+// runs the rule set on every wakeup. On a connection error it reconnects
+// with exponential backoff until ctx is cancelled.
+//
+// watermarks is shared across reconnects so a dropped/re-established
+// connection doesn't re-emit mail the account already matched.
+func watchAccount(ctx context.Context, cred creds.Credential, ruleSet []rules.Rule, hits report.OutputSink, extracts *report.TextSink, cfg Config) {
+	backoff := minBackoff
+	watermarks := make(checker.UIDWatermarks)
+	primed := false
+
+	for ctx.Err() == nil {
+		store, err := connect(cred, cfg)
+		if err != nil {
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = minBackoff
+
+		if !primed {
+			if err := checker.PrimeWatermarks(store, ruleSet, watermarks); err != nil {
+				fmt.Printf("daemon: %s: %v\n", cred.Email, err)
+			}
+			primed = true
+		}
+
+		if err := idleUntilChanged(ctx, store, ruleSet, cred.Email, hits, extracts, cfg.PollInterval, cfg.SaveAttachments, watermarks); err != nil {
+			fmt.Printf("daemon: %s: %v\n", cred.Email, err)
+		}
+		store.Logout()
+	}
+}
+
+// idleUntilChanged watches store for new mail, re-running ruleSet against
+// only the UIDs that arrived since watermarks was last advanced, until ctx
+// is cancelled or the watch itself errors (e.g. the connection dropped).
+func idleUntilChanged(ctx context.Context, store *mstore.IMAPStore, ruleSet []rules.Rule, email string, hits report.OutputSink, extracts *report.TextSink, pollInterval time.Duration, saveAttachments bool, watermarks checker.UIDWatermarks) error {
+	stop := make(chan struct{})
+	changes := make(chan struct{}, 1)
+	watchDone := make(chan error, 1)
+
+	go func() { watchDone <- store.Watch(stop, changes, pollInterval) }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(stop)
+			<-watchDone
+			return nil
+		case <-changes:
+			checker.RunRules(store, email, ruleSet, hits, extracts, saveAttachments, watermarks)
+		case err := <-watchDone:
+			return err
+		}
+	}
+}
+
+func connect(cred creds.Credential, cfg Config) (*mstore.IMAPStore, error) {
+	config, err := cfg.Directory.Config(cred.Email, cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+	if cred.Auth == creds.AuthXOAuth2 {
+		config.Auth = mstore.AuthXOAuth2
+	}
+
+	store := mstore.NewIMAPStore(config, cred.Email, cred.Secret)
+	store.InsecureSkipVerify = cfg.InsecureSkipVerify
+	if err := store.Connect(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func nextBackoff(cur time.Duration) time.Duration {
+	next := cur * 2
+	if next > maxBackoff {
+		return maxBackoff
+	}
+	return next
+}
+
+// sleep waits for d or ctx cancellation, returning false if ctx was cancelled.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package discover
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cache persists successful domain lookups to disk so repeated runs (and
+// concurrent workers within the same run) don't re-probe SRV/autoconfig/
+// autodiscover for every account on a given domain.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	loaded  bool
+}
+
+type cacheEntry struct {
+	Result    Result    `json:"result"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewCache opens (but doesn't yet read) a cache file at path with the given
+// TTL for cached entries.
+func NewCache(path string, ttl time.Duration) *Cache {
+	return &Cache{path: path, ttl: ttl}
+}
+
+func (c *Cache) ensureLoaded() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = map[string]cacheEntry{}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &c.entries)
+}
+
+// Get returns the cached result for domain, if present and not expired.
+func (c *Cache) Get(domain string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+
+	entry, ok := c.entries[domain]
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return Result{}, false
+	}
+	return entry.Result, true
+}
+
+// Put stores result for domain and flushes the cache to disk.
+func (c *Cache) Put(domain string, result Result) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+
+	c.entries[domain] = cacheEntry{Result: result, ExpiresAt: time.Now().Add(c.ttl)}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
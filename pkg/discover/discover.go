@@ -0,0 +1,224 @@
+// Package discover figures out how to reach a domain's IMAP server when it
+// isn't listed in imap_servers.json, using the same pipeline a mail client
+// would: SRV records, then Mozilla autoconfig, then Microsoft autodiscover,
+// falling back to the imap.<domain> guess.
+package discover
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"imap-checker/pkg/mstore"
+)
+
+// Result is a resolved connection target for a domain.
+type Result struct {
+	Host     string
+	Port     int
+	Security mstore.Security
+}
+
+// ServerConfig adapts a discovery Result into a mstore.ServerConfig.
+func (r Result) ServerConfig() mstore.ServerConfig {
+	return mstore.ServerConfig{Host: r.Host, Port: r.Port, Security: r.Security}
+}
+
+const httpTimeout = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// Domain resolves the IMAP server for emailAddress's domain, trying SRV,
+// autoconfig, and autodiscover in order before falling back to imap.<domain>.
+// Successful lookups are cached to disk (see NewCache) so bulk runs don't
+// re-probe every account at the same domain.
+func Domain(emailAddress string, cache *Cache) (Result, error) {
+	parts := strings.SplitN(emailAddress, "@", 2)
+	if len(parts) != 2 {
+		return Result{}, fmt.Errorf("invalid email format")
+	}
+	domain := parts[1]
+
+	if cache != nil {
+		if r, ok := cache.Get(domain); ok {
+			return r, nil
+		}
+	}
+
+	result, err := resolve(domain, emailAddress)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if cache != nil {
+		cache.Put(domain, result)
+	}
+	return result, nil
+}
+
+func resolve(domain, emailAddress string) (Result, error) {
+	if r, ok := lookupSRV(domain); ok {
+		return r, nil
+	}
+	if r, ok := lookupAutoconfig(domain, emailAddress); ok {
+		return r, nil
+	}
+	if r, ok := lookupAutodiscover(domain, emailAddress); ok {
+		return r, nil
+	}
+	return Result{Host: "imap." + domain, Port: mstore.DefaultPort, Security: mstore.SecurityTLS}, nil
+}
+
+// lookupSRV tries RFC 6186 _imaps._tcp then _imap._tcp SRV records.
+func lookupSRV(domain string) (Result, bool) {
+	if r, ok := lookupSRVService("imaps", domain, mstore.SecurityTLS); ok {
+		return r, true
+	}
+	if r, ok := lookupSRVService("imap", domain, mstore.SecurityStartTLS); ok {
+		return r, true
+	}
+	return Result{}, false
+}
+
+func lookupSRVService(service, domain string, security mstore.Security) (Result, bool) {
+	_, addrs, err := net.LookupSRV(service, "tcp", domain)
+	if err != nil || len(addrs) == 0 {
+		return Result{}, false
+	}
+
+	// net.LookupSRV already returns records sorted by priority/weight.
+	best := addrs[0]
+	port := int(best.Port)
+	switch port {
+	case 993:
+		security = mstore.SecurityTLS
+	case 143:
+		security = mstore.SecurityStartTLS
+	}
+
+	return Result{
+		Host:     strings.TrimSuffix(best.Target, "."),
+		Port:     port,
+		Security: security,
+	}, true
+}
+
+type autoconfigXML struct {
+	EmailProvider struct {
+		IncomingServer []struct {
+			Type           string `xml:"type,attr"`
+			Hostname       string `xml:"hostname"`
+			Port           int    `xml:"port"`
+			SocketType     string `xml:"socketType"`
+			Authentication string `xml:"authentication"`
+		} `xml:"incomingServer"`
+	} `xml:"emailProvider"`
+}
+
+// lookupAutoconfig fetches Mozilla's autoconfig document and picks the IMAP
+// incomingServer entry.
+func lookupAutoconfig(domain, emailAddress string) (Result, bool) {
+	url := fmt.Sprintf("https://autoconfig.%s/mail/config-v1.1.xml?emailaddress=%s", domain, emailAddress)
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return Result{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, false
+	}
+
+	var config autoconfigXML
+	if err := xml.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return Result{}, false
+	}
+
+	for _, server := range config.EmailProvider.IncomingServer {
+		if !strings.EqualFold(server.Type, "imap") {
+			continue
+		}
+		return Result{
+			Host:     server.Hostname,
+			Port:     server.Port,
+			Security: socketTypeToSecurity(server.SocketType),
+		}, true
+	}
+
+	return Result{}, false
+}
+
+type autodiscoverRequest struct {
+	XMLName xml.Name `xml:"Autodiscover"`
+	Request struct {
+		EMailAddress string `xml:"EMailAddress"`
+	} `xml:"Request"`
+}
+
+type autodiscoverResponse struct {
+	Response struct {
+		Account struct {
+			Protocol []struct {
+				Type   string `xml:"Type"`
+				Server string `xml:"Server"`
+				Port   int    `xml:"Port"`
+				SSL    string `xml:"SSL"`
+			} `xml:"Protocol"`
+		} `xml:"Account"`
+	} `xml:"Response"`
+}
+
+// lookupAutodiscover POSTs Microsoft's autodiscover request and picks the
+// IMAP protocol entry.
+func lookupAutodiscover(domain, emailAddress string) (Result, bool) {
+	req := autodiscoverRequest{}
+	req.Request.EMailAddress = emailAddress
+
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return Result{}, false
+	}
+
+	url := fmt.Sprintf("https://autodiscover.%s/autodiscover/autodiscover.xml", domain)
+	resp, err := httpClient.Post(url, "text/xml", bytes.NewReader(body))
+	if err != nil {
+		return Result{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, false
+	}
+
+	var out autodiscoverResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Result{}, false
+	}
+
+	for _, protocol := range out.Response.Account.Protocol {
+		if !strings.EqualFold(protocol.Type, "imap") {
+			continue
+		}
+		security := mstore.SecurityStartTLS
+		if strings.EqualFold(protocol.SSL, "on") {
+			security = mstore.SecurityTLS
+		}
+		return Result{Host: protocol.Server, Port: protocol.Port, Security: security}, true
+	}
+
+	return Result{}, false
+}
+
+func socketTypeToSecurity(socketType string) mstore.Security {
+	switch strings.ToUpper(socketType) {
+	case "SSL":
+		return mstore.SecurityTLS
+	case "STARTTLS":
+		return mstore.SecurityStartTLS
+	default:
+		return mstore.SecurityPlain
+	}
+}
@@ -0,0 +1,117 @@
+// Package mailbody decodes the full RFC 822 body of a fetched IMAP message
+// into plain text and attachments, using github.com/emersion/go-message.
+package mailbody
+
+import (
+	"html"
+	"io"
+	"strings"
+
+	_ "github.com/emersion/go-message/charset" // registers non-UTF-8 charset decoders
+	"github.com/emersion/go-message/mail"
+)
+
+// Attachment is a decoded non-inline part.
+type Attachment struct {
+	Filename string
+	Data     []byte
+}
+
+// Decoded holds a message's readable content.
+type Decoded struct {
+	// Text is the message's plain-text content: its text/plain parts, or
+	// tag-stripped text/html parts when no text/plain part was present.
+	Text        string
+	Attachments []Attachment
+}
+
+// Decode walks r, a full RFC 822 message, collecting its text content and,
+// when saveAttachments is true, its attachment bytes.
+func Decode(r io.Reader, saveAttachments bool) (Decoded, error) {
+	reader, err := mail.CreateReader(r)
+	if reader == nil {
+		return Decoded{}, err
+	}
+	defer reader.Close()
+
+	var out Decoded
+	var htmlFallback strings.Builder
+
+	for {
+		part, perr := reader.NextPart()
+		if perr == io.EOF {
+			break
+		}
+		if perr != nil {
+			break
+		}
+
+		switch h := part.Header.(type) {
+		case *mail.InlineHeader:
+			contentType, _, _ := h.ContentType()
+			body, _ := io.ReadAll(part.Body)
+			switch contentType {
+			case "text/plain":
+				out.Text += string(body)
+			case "text/html":
+				htmlFallback.WriteString(stripTags(string(body)))
+			}
+		case *mail.AttachmentHeader:
+			if !saveAttachments {
+				continue
+			}
+			filename, _ := h.Filename()
+			data, _ := io.ReadAll(part.Body)
+			out.Attachments = append(out.Attachments, Attachment{Filename: filename, Data: data})
+		}
+	}
+
+	if out.Text == "" {
+		out.Text = htmlFallback.String()
+	}
+
+	return out, nil
+}
+
+// stripTags removes HTML tags, leaving the text content between them. The
+// contents of <script>/<style> elements are dropped entirely rather than
+// kept as text, and entities (e.g. "&amp;") are decoded in what remains.
+func stripTags(htmlText string) string {
+	var b strings.Builder
+	var tag strings.Builder
+	inTag := false
+	skipUntil := ""
+
+	for _, r := range htmlText {
+		switch {
+		case r == '<':
+			inTag = true
+			tag.Reset()
+		case r == '>':
+			inTag = false
+			name := tagName(tag.String())
+			switch {
+			case skipUntil != "" && name == "/"+skipUntil:
+				skipUntil = ""
+			case skipUntil == "" && (name == "script" || name == "style"):
+				skipUntil = name
+			}
+		case inTag:
+			tag.WriteRune(r)
+		case skipUntil == "":
+			b.WriteRune(r)
+		}
+	}
+
+	return html.UnescapeString(b.String())
+}
+
+// tagName extracts the lowercase element name from a tag's inner text, e.g.
+// "script type=\"text/javascript\"" -> "script", "/style" -> "/style".
+func tagName(inner string) string {
+	inner = strings.ToLower(strings.TrimSpace(inner))
+	if end := strings.IndexAny(inner, " \t\n\r"); end >= 0 {
+		inner = inner[:end]
+	}
+	return inner
+}
@@ -0,0 +1,38 @@
+package mstore
+
+import (
+	"time"
+
+	idle "github.com/emersion/go-imap-idle"
+	"github.com/emersion/go-imap/client"
+)
+
+// Watch blocks, sending a signal on changes every time the selected mailbox
+// gains new or updated messages, until stop is closed or the connection
+// drops. It uses IMAP IDLE when the server advertises the capability,
+// falling back to a NOOP poll every pollInterval otherwise.
+func (s *IMAPStore) Watch(stop <-chan struct{}, changes chan<- struct{}, pollInterval time.Duration) error {
+	updates := make(chan client.Update, 16)
+	s.client.Updates = updates
+	defer func() { s.client.Updates = nil }()
+
+	idleClient := idle.NewClient(s.client)
+
+	done := make(chan error, 1)
+	go func() { done <- idleClient.IdleWithFallback(stop, pollInterval) }()
+
+	for {
+		select {
+		case upd := <-updates:
+			switch upd.(type) {
+			case *client.MailboxUpdate, *client.MessageUpdate:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		case err := <-done:
+			return err
+		}
+	}
+}
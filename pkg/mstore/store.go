@@ -0,0 +1,214 @@
+// Package mstore defines a pluggable mailbox store abstraction so the
+// checker loop can run against any backend (IMAP today, JMAP/POP3/Proton
+// bridge later) without depending on a concrete client implementation.
+package mstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// Store is the minimal surface the checker needs from a mailbox backend.
+type Store interface {
+	// Connect dials the server and authenticates.
+	Connect() error
+	// Select opens a mailbox for subsequent searches/fetches.
+	Select(mailbox string, readOnly bool) (*imap.MailboxStatus, error)
+	// Search returns the UIDs of messages matching criteria.
+	Search(criteria *imap.SearchCriteria) ([]uint32, error)
+	// Fetch retrieves the requested items for the given UIDs.
+	Fetch(uids []uint32, items []imap.FetchItem) ([]*imap.Message, error)
+	// Logout closes the session.
+	Logout() error
+}
+
+// Security selects how the connection is encrypted.
+type Security string
+
+const (
+	SecurityTLS         Security = "tls"      // implicit TLS (dial straight into TLS, usually port 993)
+	SecurityStartTLS    Security = "starttls" // plaintext dial, then STARTTLS upgrade (usually port 143)
+	SecurityPlain       Security = "plain"    // no encryption at all
+	DefaultPort                  = 993
+	DefaultSTARTTLSPort          = 143
+)
+
+// Auth selects how credentials are presented to the server.
+type Auth string
+
+const (
+	AuthPlain   Auth = "plain"
+	AuthXOAuth2 Auth = "xoauth2"
+)
+
+// ServerConfig describes how to reach and authenticate against a domain's
+// IMAP server, as loaded from imap_servers.json.
+type ServerConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Security Security `json:"security"`
+	Auth     Auth     `json:"auth"`
+	CABundle string   `json:"ca_bundle"`
+}
+
+// Addr returns the "host:port" string to dial, applying the security-mode
+// default port when Port is unset.
+func (c ServerConfig) Addr() string {
+	port := c.Port
+	if port == 0 {
+		if c.Security == SecurityStartTLS {
+			port = DefaultSTARTTLSPort
+		} else {
+			port = DefaultPort
+		}
+	}
+	return c.Host + ":" + strconv.Itoa(port)
+}
+
+// IMAPStore is a Store backed by github.com/emersion/go-imap/client.
+type IMAPStore struct {
+	Config ServerConfig
+	Email  string
+	// Secret is the password (Auth == AuthPlain) or bearer token
+	// (Auth == AuthXOAuth2) used to authenticate.
+	Secret string
+
+	// InsecureSkipVerify disables TLS certificate verification; intended
+	// for self-hosted servers with untrusted or self-signed certs.
+	InsecureSkipVerify bool
+
+	client *client.Client
+}
+
+// NewIMAPStore builds an IMAPStore for the given server config and
+// credentials. An empty Config.Security defaults to SecurityTLS and an empty
+// Config.Auth to AuthPlain.
+func NewIMAPStore(config ServerConfig, email, secret string) *IMAPStore {
+	if config.Security == "" {
+		config.Security = SecurityTLS
+	}
+	if config.Auth == "" {
+		config.Auth = AuthPlain
+	}
+	return &IMAPStore{Config: config, Email: email, Secret: secret}
+}
+
+func (s *IMAPStore) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: s.Config.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+
+	if s.Config.CABundle != "" {
+		pem, err := os.ReadFile(s.Config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", s.Config.CABundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+func (s *IMAPStore) Connect() error {
+	addr := s.Config.Addr()
+
+	var c *client.Client
+	var err error
+
+	switch s.Config.Security {
+	case SecurityStartTLS:
+		c, err = client.Dial(addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %v", err)
+		}
+		tlsConfig, terr := s.tlsConfig()
+		if terr != nil {
+			c.Logout()
+			return terr
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Logout()
+			return fmt.Errorf("starttls failed: %v", err)
+		}
+	case SecurityPlain:
+		c, err = client.Dial(addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %v", err)
+		}
+	default: // SecurityTLS
+		tlsConfig, terr := s.tlsConfig()
+		if terr != nil {
+			return terr
+		}
+		c, err = client.DialTLS(addr, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %v", err)
+		}
+	}
+
+	if err := s.authenticate(c); err != nil {
+		c.Logout()
+		return err
+	}
+
+	s.client = c
+	return nil
+}
+
+func (s *IMAPStore) authenticate(c *client.Client) error {
+	if s.Config.Auth == AuthXOAuth2 {
+		authClient := newXoauth2Client(s.Email, s.Secret)
+		if err := c.Authenticate(authClient); err != nil {
+			return fmt.Errorf("xoauth2 authentication failed: %v", err)
+		}
+		return nil
+	}
+
+	if err := c.Login(s.Email, s.Secret); err != nil {
+		return fmt.Errorf("login failed: %v", err)
+	}
+	return nil
+}
+
+func (s *IMAPStore) Select(mailbox string, readOnly bool) (*imap.MailboxStatus, error) {
+	return s.client.Select(mailbox, readOnly)
+}
+
+func (s *IMAPStore) Search(criteria *imap.SearchCriteria) ([]uint32, error) {
+	return s.client.Search(criteria)
+}
+
+func (s *IMAPStore) Fetch(uids []uint32, items []imap.FetchItem) ([]*imap.Message, error) {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.client.Fetch(seqSet, items, messages)
+	}()
+
+	var result []*imap.Message
+	for msg := range messages {
+		result = append(result, msg)
+	}
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *IMAPStore) Logout() error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Logout()
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jsonlHit is the on-disk shape of a Hit written by JSONLSink, one object
+// per line of hitsPath.
+type jsonlHit struct {
+	Account string            `json:"account"`
+	Rule    string            `json:"rule"`
+	UID     uint32            `json:"uid"`
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Subject string            `json:"subject"`
+	Date    string            `json:"date"`
+	Snippet string            `json:"snippet"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// JSONLSink writes each hit and account result as one JSON object per line,
+// for downstream tools like jq to consume instead of scraping text files.
+type JSONLSink struct {
+	mu       sync.Mutex
+	hits     *os.File
+	accounts *os.File
+}
+
+// NewJSONLSink opens hitsPath and accountsPath for append, creating them if
+// they don't already exist.
+func NewJSONLSink(hitsPath, accountsPath string) (*JSONLSink, error) {
+	hits, err := os.OpenFile(hitsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", hitsPath, err)
+	}
+
+	accounts, err := os.OpenFile(accountsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		hits.Close()
+		return nil, fmt.Errorf("failed to open %s: %v", accountsPath, err)
+	}
+
+	return &JSONLSink{hits: hits, accounts: accounts}, nil
+}
+
+func (s *JSONLSink) WriteHit(h Hit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeJSONLine(s.hits, jsonlHit{
+		Account: h.Account,
+		Rule:    h.Rule,
+		UID:     h.UID,
+		From:    h.From,
+		To:      h.To,
+		Subject: h.Subject,
+		Date:    h.Date,
+		Snippet: h.Snippet,
+		Headers: h.Headers,
+	})
+}
+
+func (s *JSONLSink) WriteAccount(a AccountResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeJSONLine(s.accounts, a)
+}
+
+func (s *JSONLSink) Close() error {
+	err1 := s.hits.Close()
+	err2 := s.accounts.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+func writeJSONLine(f *os.File, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
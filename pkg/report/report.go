@@ -0,0 +1,167 @@
+// Package report owns everything the checker prints or writes to disk: the
+// progress bar and the pluggable sinks hit/account results get written to.
+package report
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Progress tracks and renders checked/live/total counters on a progress bar.
+type Progress struct {
+	checked uint64
+	live    uint64
+	total   uint64
+	bar     *progressbar.ProgressBar
+}
+
+// NewProgress creates a Progress bar for the given number of accounts.
+func NewProgress(total int) *Progress {
+	p := &Progress{total: uint64(total)}
+	p.bar = progressbar.NewOptions(total,
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionSetDescription("[cyan]Starting...[reset]"),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "[green]█[reset]",
+			SaucerHead:    "[green]█[reset]",
+			SaucerPadding: "░",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Println()
+		}))
+	return p
+}
+
+// MarkChecked records that one more account finished processing, marking it
+// live if applicable, and advances the bar.
+func (p *Progress) MarkChecked(live bool) {
+	if live {
+		atomic.AddUint64(&p.live, 1)
+	}
+	checked := atomic.AddUint64(&p.checked, 1)
+
+	desc := fmt.Sprintf("[cyan]Checked:[reset] %d [green]Live:[reset] %d [yellow]Total:[reset] %d",
+		checked, atomic.LoadUint64(&p.live), p.total)
+	p.bar.Describe(desc)
+	p.bar.Add(1)
+}
+
+// Hit is one message that matched a rule, passed to every OutputSink.
+type Hit struct {
+	Account string
+	Rule    string
+	UID     uint32
+	From    string
+	To      string
+	Subject string
+	Date    string
+	Snippet string
+	Headers map[string]string
+}
+
+// AccountResult records the outcome of checking one account, passed to
+// every OutputSink once the account finishes processing.
+type AccountResult struct {
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// OutputSink is implemented by every backend the checker can report rule
+// hits and account results to. Implementations must be safe for concurrent
+// use, since accounts are checked concurrently.
+type OutputSink interface {
+	WriteHit(Hit) error
+	WriteAccount(AccountResult) error
+	Close() error
+}
+
+// MultiSink fans writes out to every sink it wraps, in order, stopping and
+// returning the first error encountered.
+type MultiSink struct {
+	sinks []OutputSink
+}
+
+// NewMultiSink combines sinks into a single OutputSink.
+func NewMultiSink(sinks ...OutputSink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) WriteHit(h Hit) error {
+	for _, s := range m.sinks {
+		if err := s.WriteHit(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiSink) WriteAccount(a AccountResult) error {
+	for _, s := range m.sinks {
+		if err := s.WriteAccount(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiSink) Close() error {
+	var firstErr error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// textHitSink adapts TextSink for inclusion in a MultiSink without taking
+// ownership of its Close: the caller always owns textSink's lifetime
+// directly, since it also serves live.txt and the extractor files
+// regardless of which --output sinks are selected.
+type textHitSink struct{ *TextSink }
+
+func (textHitSink) Close() error { return nil }
+
+// NewOutputSinks builds the OutputSink selected by spec, a comma-separated
+// list of "text", "jsonl" and "sqlite". textSink is reused for the "text"
+// entry so its hits_<rule>.txt output stays shared with the rest of the
+// program instead of opening a second handle.
+func NewOutputSinks(spec string, textSink *TextSink) (OutputSink, error) {
+	var sinks []OutputSink
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case "text":
+			sinks = append(sinks, textHitSink{textSink})
+		case "jsonl":
+			s, err := NewJSONLSink("hits.jsonl", "accounts.jsonl")
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, s)
+		case "sqlite":
+			s, err := NewSQLiteSink("checker.db")
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, s)
+		case "":
+			// tolerate stray commas
+		default:
+			return nil, fmt.Errorf("unknown output sink %q", name)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("no output sinks selected")
+	}
+	return NewMultiSink(sinks...), nil
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the two tables SQLiteSink writes to if they don't
+// already exist, along with the indices analysis queries lean on.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS accounts (
+	email      TEXT PRIMARY KEY,
+	status     TEXT NOT NULL,
+	checked_at DATETIME NOT NULL,
+	error      TEXT
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id   TEXT NOT NULL,
+	uid          INTEGER NOT NULL,
+	from_addr    TEXT,
+	subject      TEXT,
+	date         TEXT,
+	rule         TEXT NOT NULL,
+	body_snippet TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_messages_account_date ON messages(account_id, date);
+CREATE INDEX IF NOT EXISTS idx_messages_rule ON messages(rule);
+`
+
+// SQLiteSink writes hits and account results into a CGO-free SQLite
+// database, so they can be queried with SQL instead of scraping text files.
+type SQLiteSink struct {
+	db *sql.DB
+}
+
+// NewSQLiteSink opens (creating if needed) the SQLite database at path and
+// ensures its schema exists. Accounts are checked concurrently, so the
+// connection is tuned for many writers: WAL lets readers and the writer
+// coexist, busy_timeout makes SQLITE_BUSY block-and-retry instead of
+// failing instantly, and capping the pool at one connection serializes
+// writes through database/sql instead of racing SQLite's own locking.
+func NewSQLiteSink(path string) (*SQLiteSink, error) {
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema in %s: %v", path, err)
+	}
+
+	return &SQLiteSink{db: db}, nil
+}
+
+func (s *SQLiteSink) WriteHit(h Hit) error {
+	_, err := s.db.Exec(
+		`INSERT INTO messages (account_id, uid, from_addr, subject, date, rule, body_snippet) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		h.Account, h.UID, h.From, h.Subject, h.Date, h.Rule, h.Snippet,
+	)
+	return err
+}
+
+func (s *SQLiteSink) WriteAccount(a AccountResult) error {
+	_, err := s.db.Exec(
+		`INSERT INTO accounts (email, status, checked_at, error) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(email) DO UPDATE SET status = excluded.status, checked_at = excluded.checked_at, error = excluded.error`,
+		a.Email, a.Status, a.CheckedAt, a.Error,
+	)
+	return err
+}
+
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// TextSink writes each rule's hits to its own hits_<rule>.txt file and live
+// accounts to live.txt, mirroring the checker's original plain-text output.
+type TextSink struct {
+	hitsMu sync.Mutex
+
+	liveFile *os.File
+}
+
+// NewTextSink opens live.txt for synchronous appends; per-rule hit files are
+// opened and closed again on each write.
+func NewTextSink(livePath string) (*TextSink, error) {
+	liveFile, err := os.OpenFile(livePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY|os.O_SYNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", livePath, err)
+	}
+	return &TextSink{liveFile: liveFile}, nil
+}
+
+// WriteRuleHit appends content to hits_<rule>.txt.
+func (s *TextSink) WriteRuleHit(rule, content string) error {
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+
+	path := fmt.Sprintf("hits_%s.txt", rule)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	return err
+}
+
+// WriteHit implements OutputSink by formatting h as a text block appended to
+// hits_<rule>.txt.
+func (s *TextSink) WriteHit(h Hit) error {
+	content := fmt.Sprintf("\nFrom: %s\nTo: %s\nSubject: %s\nDate: %s\nAccount: %s\n---\n",
+		h.From, h.To, h.Subject, h.Date, h.Account)
+	return s.WriteRuleHit(h.Rule, content)
+}
+
+// WriteAccount implements OutputSink. Account status for the text sink is
+// the live.txt credential dump written via WriteLive, which the caller
+// drives directly (it needs the account's secret, which AccountResult
+// intentionally omits), so there is nothing further to do here.
+func (s *TextSink) WriteAccount(AccountResult) error {
+	return nil
+}
+
+// WriteExtract appends one JSON-encoded record as a line to
+// extracts_<rule>.jsonl, the structured output of a rule's regex extractors.
+func (s *TextSink) WriteExtract(rule string, record any) error {
+	s.hitsMu.Lock()
+	defer s.hitsMu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("extracts_%s.jsonl", rule)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// WriteLive appends a line to live.txt.
+func (s *TextSink) WriteLive(line string) error {
+	_, err := fmt.Fprintln(s.liveFile, line)
+	return err
+}
+
+// Close releases the open file handles.
+func (s *TextSink) Close() error {
+	return s.liveFile.Close()
+}
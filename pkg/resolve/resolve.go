@@ -0,0 +1,53 @@
+// Package resolve turns an account's email address into a mstore.ServerConfig,
+// checking the imap_servers.json directory before falling back to
+// pkg/discover. It's the one place that needs to know about both.
+package resolve
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"imap-checker/pkg/discover"
+	"imap-checker/pkg/mstore"
+)
+
+// Directory maps email domains to explicit server configs loaded from
+// imap_servers.json.
+type Directory map[string]mstore.ServerConfig
+
+// LoadDirectory reads imap_servers.json from path.
+func LoadDirectory(path string) (Directory, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IMAP servers file: %v", err)
+	}
+
+	var dir Directory
+	if err := json.Unmarshal(file, &dir); err != nil {
+		return nil, fmt.Errorf("failed to parse IMAP servers file: %v", err)
+	}
+	return dir, nil
+}
+
+// Config resolves the server config for an account: imap_servers.json takes
+// priority, then SRV/autoconfig/autodiscover, falling back to the
+// imap.<domain> guess over implicit TLS.
+func (d Directory) Config(email string, cache *discover.Cache) (mstore.ServerConfig, error) {
+	parts := strings.SplitN(email, "@", 2)
+	if len(parts) != 2 {
+		return mstore.ServerConfig{}, fmt.Errorf("invalid email format")
+	}
+	domain := parts[1]
+
+	if config, ok := d[domain]; ok {
+		return config, nil
+	}
+
+	result, err := discover.Domain(email, cache)
+	if err != nil {
+		return mstore.ServerConfig{}, err
+	}
+	return result.ServerConfig(), nil
+}
@@ -0,0 +1,174 @@
+// Package rules loads a rules.yaml/rules.json file describing what to
+// search for in each mailbox, and turns each rule into an imap.SearchCriteria.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"gopkg.in/yaml.v3"
+)
+
+// HeaderFilter matches an arbitrary header name/value pair.
+type HeaderFilter struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// Rule is one named search to run against every account's mailbox.
+type Rule struct {
+	Name            string        `yaml:"name" json:"name"`
+	Mailbox         string        `yaml:"mailbox" json:"mailbox"`
+	From            string        `yaml:"from" json:"from"`
+	To              string        `yaml:"to" json:"to"`
+	SubjectContains string        `yaml:"subject_contains" json:"subject_contains"`
+	BodyContains    string        `yaml:"body_contains" json:"body_contains"`
+	Since           string        `yaml:"since" json:"since"`
+	Before          string        `yaml:"before" json:"before"`
+	UnseenOnly      bool          `yaml:"unseen_only" json:"unseen_only"`
+	Header          *HeaderFilter `yaml:"header" json:"header"`
+	Extract         []string      `yaml:"extract" json:"extract"`
+	Extractors      []Extractor   `yaml:"extractors" json:"extractors"`
+}
+
+// Extractor names a regular expression to run against a matched message's
+// decoded body; capture groups become the extracted fields (e.g. a 6-digit
+// PIN from "pin:\s*(\d{6})").
+type Extractor struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// CompiledExtractor is an Extractor with its pattern compiled.
+type CompiledExtractor struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// CompiledExtractors compiles this rule's extractor patterns.
+func (r Rule) CompiledExtractors() ([]CompiledExtractor, error) {
+	compiled := make([]CompiledExtractor, 0, len(r.Extractors))
+	for _, e := range r.Extractors {
+		re, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: extractor %q: invalid pattern: %v", r.Name, e.Name, err)
+		}
+		compiled = append(compiled, CompiledExtractor{Name: e.Name, Regexp: re})
+	}
+	return compiled, nil
+}
+
+// LoadFile reads rules from path, picking a parser based on the file
+// extension (.json, otherwise YAML).
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %v", err)
+	}
+
+	var rules []Rule
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &rules)
+	} else {
+		err = yaml.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %v", err)
+	}
+
+	return rules, nil
+}
+
+// MailboxOrDefault returns the mailbox this rule searches, defaulting to INBOX.
+func (r Rule) MailboxOrDefault() string {
+	if r.Mailbox == "" {
+		return "INBOX"
+	}
+	return r.Mailbox
+}
+
+// Extracts reports whether the named envelope/body part ("from", "to",
+// "subject", "date", or "body") should be included in a matched message's
+// hit. An empty Extract list means every part is included, so rules
+// written before extract existed keep dumping everything.
+func (r Rule) Extracts(part string) bool {
+	if len(r.Extract) == 0 {
+		return true
+	}
+	for _, p := range r.Extract {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchCriteria builds the IMAP search criteria for this rule.
+func (r Rule) SearchCriteria() (*imap.SearchCriteria, error) {
+	criteria := imap.NewSearchCriteria()
+
+	if r.From != "" {
+		criteria.Header.Add("From", r.From)
+	}
+	if r.To != "" {
+		criteria.Header.Add("To", r.To)
+	}
+	if r.SubjectContains != "" {
+		criteria.Header.Add("Subject", r.SubjectContains)
+	}
+	if r.BodyContains != "" {
+		criteria.Body = append(criteria.Body, r.BodyContains)
+	}
+	if r.Header != nil && r.Header.Name != "" {
+		criteria.Header.Add(r.Header.Name, r.Header.Value)
+	}
+	if r.UnseenOnly {
+		criteria.WithoutFlags = append(criteria.WithoutFlags, imap.SeenFlag)
+	}
+
+	if r.Since != "" {
+		t, err := parseDate(r.Since)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid since %q: %v", r.Name, r.Since, err)
+		}
+		criteria.Since = t
+	}
+	if r.Before != "" {
+		t, err := parseDate(r.Before)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid before %q: %v", r.Name, r.Before, err)
+		}
+		criteria.Before = t
+	}
+
+	return criteria, nil
+}
+
+// parseDate accepts an absolute "2006-01-02" date or a relative offset like
+// "-30d"/"-6h" from now.
+func parseDate(s string) (time.Time, error) {
+	if len(s) > 1 && (s[0] == '-' || s[0] == '+') {
+		unit := s[len(s)-1]
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err == nil {
+			if s[0] == '-' {
+				n = -n
+			}
+			switch unit {
+			case 'd':
+				return time.Now().AddDate(0, 0, n), nil
+			case 'h':
+				return time.Now().Add(time.Duration(n) * time.Hour), nil
+			}
+		}
+	}
+
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	t.Run("absolute date", func(t *testing.T) {
+		got, err := parseDate("2024-01-15")
+		if err != nil {
+			t.Fatalf("parseDate: %v", err)
+		}
+		want := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		if _, err := parseDate("not-a-date"); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want time.Duration // offset from time.Now(), within tolerance
+	}{
+		{"relative days ago", "-30d", -30 * 24 * time.Hour},
+		{"relative days ahead", "+7d", 7 * 24 * time.Hour},
+		{"relative hours ago", "-6h", -6 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.in)
+			if err != nil {
+				t.Fatalf("parseDate(%q): %v", tt.in, err)
+			}
+			want := time.Now().Add(tt.want)
+			if diff := got.Sub(want); diff < -2*time.Second || diff > 2*time.Second {
+				t.Errorf("parseDate(%q) = %v, want ~%v (diff %v)", tt.in, got, want, diff)
+			}
+		})
+	}
+}
+
+func TestRuleSearchCriteria(t *testing.T) {
+	t.Run("builds header, body and flag criteria", func(t *testing.T) {
+		r := Rule{
+			Name:            "promo",
+			From:            "booking.com",
+			To:              "me@example.com",
+			SubjectContains: "confirmation",
+			BodyContains:    "reservation",
+			UnseenOnly:      true,
+			Header:          &HeaderFilter{Name: "X-Mailer", Value: "Acme"},
+		}
+
+		criteria, err := r.SearchCriteria()
+		if err != nil {
+			t.Fatalf("SearchCriteria: %v", err)
+		}
+
+		if got := criteria.Header.Get("From"); got != "booking.com" {
+			t.Errorf("From header = %q, want %q", got, "booking.com")
+		}
+		if got := criteria.Header.Get("To"); got != "me@example.com" {
+			t.Errorf("To header = %q, want %q", got, "me@example.com")
+		}
+		if got := criteria.Header.Get("Subject"); got != "confirmation" {
+			t.Errorf("Subject header = %q, want %q", got, "confirmation")
+		}
+		if got := criteria.Header.Get("X-Mailer"); got != "Acme" {
+			t.Errorf("X-Mailer header = %q, want %q", got, "Acme")
+		}
+		if len(criteria.Body) != 1 || criteria.Body[0] != "reservation" {
+			t.Errorf("Body = %v, want [%q]", criteria.Body, "reservation")
+		}
+		if len(criteria.WithoutFlags) != 1 || criteria.WithoutFlags[0] != "\\Seen" {
+			t.Errorf("WithoutFlags = %v, want [\\Seen]", criteria.WithoutFlags)
+		}
+	})
+
+	t.Run("since and before are parsed into the criteria", func(t *testing.T) {
+		r := Rule{Name: "windowed", Since: "2024-01-01", Before: "2024-02-01"}
+
+		criteria, err := r.SearchCriteria()
+		if err != nil {
+			t.Fatalf("SearchCriteria: %v", err)
+		}
+
+		wantSince := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		wantBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		if !criteria.Since.Equal(wantSince) {
+			t.Errorf("Since = %v, want %v", criteria.Since, wantSince)
+		}
+		if !criteria.Before.Equal(wantBefore) {
+			t.Errorf("Before = %v, want %v", criteria.Before, wantBefore)
+		}
+	})
+
+	t.Run("invalid since is rejected", func(t *testing.T) {
+		r := Rule{Name: "bad", Since: "not-a-date"}
+		if _, err := r.SearchCriteria(); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+
+	t.Run("empty rule produces no-op criteria", func(t *testing.T) {
+		r := Rule{Name: "empty"}
+		criteria, err := r.SearchCriteria()
+		if err != nil {
+			t.Fatalf("SearchCriteria: %v", err)
+		}
+		if len(criteria.Body) != 0 || len(criteria.WithoutFlags) != 0 {
+			t.Errorf("expected no body/flag criteria, got %+v", criteria)
+		}
+	})
+}
+
+func TestRuleMailboxOrDefault(t *testing.T) {
+	if got := (Rule{}).MailboxOrDefault(); got != "INBOX" {
+		t.Errorf("MailboxOrDefault() = %q, want INBOX", got)
+	}
+	if got := (Rule{Mailbox: "Archive"}).MailboxOrDefault(); got != "Archive" {
+		t.Errorf("MailboxOrDefault() = %q, want Archive", got)
+	}
+}